@@ -0,0 +1,132 @@
+package smtp
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// SignDKIM signs the message per RFC 6376 using relaxed/relaxed
+// canonicalization over the headers named in headers (in the order
+// given) plus the rendered MIME body, and stores the resulting
+// DKIM-Signature header so it is emitted ahead of every other header by
+// String().
+// @example
+// ```javascript
+// const smtp = require('nuclei/smtp');
+// const message = new smtp.SMTPMessage();
+// message.From('xyz@projectdiscovery.io');
+// message.SignDKIM('default', 'projectdiscovery.io', privateKeyPem, ['from', 'to', 'subject']);
+// ```
+func (s *SMTPMessage) SignDKIM(selector, domain string, privateKeyPEM []byte, headers []string) (*SMTPMessage, error) {
+	key, err := parseRSAPrivateKey(privateKeyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse dkim private key: %w", err)
+	}
+
+	rawHeaders, body := s.render()
+	parsed := parseRenderedHeaders(rawHeaders)
+
+	bodyHash := sha256.Sum256(canonicalizeBodyRelaxed(body))
+
+	signedHeaders := make([]string, 0, len(headers))
+	var canon bytes.Buffer
+	for _, name := range headers {
+		value, ok := parsed[strings.ToLower(name)]
+		if !ok {
+			continue
+		}
+		signedHeaders = append(signedHeaders, strings.ToLower(name))
+		canon.WriteString(canonicalizeHeaderRelaxed(name, value))
+		canon.WriteString("\r\n")
+	}
+
+	dkimHeader := fmt.Sprintf(
+		"DKIM-Signature: v=1; a=rsa-sha256; c=relaxed/relaxed; d=%s; s=%s; h=%s; bh=%s; b=",
+		domain, selector, strings.Join(signedHeaders, ":"), base64.StdEncoding.EncodeToString(bodyHash[:]),
+	)
+	// the DKIM-Signature header is itself signed, with an empty b= tag and
+	// no trailing CRLF
+	canon.WriteString(canonicalizeHeaderRelaxed("dkim-signature", strings.TrimPrefix(dkimHeader, "DKIM-Signature:")))
+
+	digest := sha256.Sum256(canon.Bytes())
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return nil, fmt.Errorf("could not sign dkim digest: %w", err)
+	}
+
+	s.dkimSignature = dkimHeader + base64.StdEncoding.EncodeToString(sig) + "\r\n"
+	return s, nil
+}
+
+// parseRSAPrivateKey decodes a PEM-encoded RSA private key, accepting
+// both PKCS#1 ("RSA PRIVATE KEY") and PKCS#8 ("PRIVATE KEY") encodings.
+func parseRSAPrivateKey(privateKeyPEM []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(privateKeyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("could not decode pem block")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is not an rsa key")
+	}
+	return rsaKey, nil
+}
+
+var headerFoldingRegexp = regexp.MustCompile(`\r\n[ \t]+`)
+
+// parseRenderedHeaders splits a rendered CRLF header block (as returned by
+// render()) into a lowercase name -> value map, unfolding any
+// continuation lines first.
+func parseRenderedHeaders(raw string) map[string]string {
+	unfolded := headerFoldingRegexp.ReplaceAllString(raw, " ")
+	headers := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimRight(unfolded, "\r\n"), "\r\n") {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		headers[strings.ToLower(strings.TrimSpace(name))] = strings.TrimSpace(value)
+	}
+	return headers
+}
+
+// canonicalizeHeaderRelaxed applies DKIM "relaxed" header canonicalization
+// (RFC 6376 section 3.4.2): lowercase the name, collapse runs of
+// whitespace in the value to a single space, and trim.
+func canonicalizeHeaderRelaxed(name, value string) string {
+	folded := strings.Join(strings.Fields(value), " ")
+	return fmt.Sprintf("%s:%s", strings.ToLower(name), folded)
+}
+
+var internalWSPRegexp = regexp.MustCompile(`[ \t]+`)
+
+// canonicalizeBodyRelaxed applies DKIM "relaxed" body canonicalization
+// (RFC 6376 section 3.4.4): every run of WSP within a line is reduced to
+// a single space, trailing whitespace is stripped from every line, and
+// trailing empty lines are collapsed to a single trailing CRLF.
+func canonicalizeBodyRelaxed(body string) []byte {
+	lines := strings.Split(body, "\r\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(internalWSPRegexp.ReplaceAllString(line, " "), " ")
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return []byte(strings.Join(lines, "\r\n") + "\r\n")
+}
@@ -0,0 +1,72 @@
+package smtp
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"github.com/emersion/go-msgauth/dkim"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignDKIM(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+
+	pubDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	pubB64 := base64.StdEncoding.EncodeToString(pubDER)
+
+	message := &SMTPMessage{}
+	message.From("sender@example.com")
+	message.To("recipient@example.com")
+	message.Subject("dkim test")
+	message.Body([]byte("hello from a signed message"))
+	message.HTMLBody([]byte("<p>hello from a signed message</p>"))
+
+	_, err = message.SignDKIM("default", "example.com", keyPEM, []string{"from", "to", "subject"})
+	require.NoError(t, err)
+
+	raw := message.String()
+	require.True(t, strings.HasPrefix(raw, "DKIM-Signature: "))
+
+	verifications, err := dkim.VerifyWithOptions(strings.NewReader(raw), &dkim.VerifyOptions{
+		LookupTXT: func(domain string) ([]string, error) {
+			return []string{"v=DKIM1; k=rsa; p=" + pubB64}, nil
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, verifications, 1)
+	require.NoError(t, verifications[0].Err)
+	require.Equal(t, "example.com", verifications[0].Domain)
+}
+
+func TestSignDKIMStableAcrossRenders(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+
+	message := &SMTPMessage{}
+	message.From("sender@example.com")
+	message.To("recipient@example.com")
+	message.Subject("dkim test")
+	message.Body([]byte("hello"))
+	message.HTMLBody([]byte("<p>hello</p>"))
+	message.AddAttachment("file.txt", []byte("attachment data"))
+
+	_, err = message.SignDKIM("default", "example.com", keyPEM, []string{"from", "to", "subject"})
+	require.NoError(t, err)
+
+	// the rendered MIME boundaries must stay fixed after signing, or the
+	// bh=/b= tags would no longer match the message actually transmitted.
+	require.Equal(t, message.String(), message.String())
+}
@@ -3,12 +3,24 @@ package smtp
 import (
 	"bufio"
 	"bytes"
+	"crypto/rand"
 	"encoding/base64"
+	"fmt"
+	"mime/quotedprintable"
 	"net/textproto"
 	"strings"
 )
 
 type (
+	// attachmentPart is a single file-backed MIME part, used for both
+	// inline embeds (multipart/related) and regular attachments
+	// (multipart/mixed).
+	attachmentPart struct {
+		filename string
+		data     []byte
+		cid      string
+	}
+
 	// SMTPMessage is a message to be sent over SMTP
 	// @example
 	// ```javascript
@@ -17,14 +29,23 @@ type (
 	// message.From('xyz@projectdiscovery.io');
 	// ```
 	SMTPMessage struct {
-		from       string
-		to         []string
-		sub        string
-		msg        []byte
-		user       string
-		pass       string
-		attachment string
-		attachData []byte
+		from        string
+		to          []string
+		cc          []string
+		bcc         []string
+		sub         string
+		msg         []byte
+		html        []byte
+		embeds      []attachmentPart
+		attachments []attachmentPart
+		headers     [][2]string
+		user        string
+		pass        string
+
+		dkimSignature string
+		smime         *smimePart
+
+		cachedBody *mimePart
 	}
 )
 
@@ -52,6 +73,43 @@ func (s *SMTPMessage) To(email string) *SMTPMessage {
 	return s
 }
 
+// Cc adds a carbon-copy recipient to the message. Unlike Bcc, Cc
+// recipients are written to the rendered Cc header.
+// @example
+// ```javascript
+// const smtp = require('nuclei/smtp');
+// const message = new smtp.SMTPMessage();
+// message.Cc('xyz@projectdiscovery.io');
+// ```
+func (s *SMTPMessage) Cc(email string) *SMTPMessage {
+	s.cc = append(s.cc, email)
+	return s
+}
+
+// Bcc adds a blind-carbon-copy recipient to the message envelope. Bcc
+// recipients are used when transmitting the message but are never
+// written to the rendered headers.
+// @example
+// ```javascript
+// const smtp = require('nuclei/smtp');
+// const message = new smtp.SMTPMessage();
+// message.Bcc('xyz@projectdiscovery.io');
+// ```
+func (s *SMTPMessage) Bcc(email string) *SMTPMessage {
+	s.bcc = append(s.bcc, email)
+	return s
+}
+
+// Recipients returns every envelope recipient (to, cc and bcc) the
+// message should be transmitted to.
+func (s *SMTPMessage) Recipients() []string {
+	recipients := make([]string, 0, len(s.to)+len(s.cc)+len(s.bcc))
+	recipients = append(recipients, s.to...)
+	recipients = append(recipients, s.cc...)
+	recipients = append(recipients, s.bcc...)
+	return recipients
+}
+
 // Subject adds the subject field to the message
 // @example
 // ```javascript
@@ -64,7 +122,8 @@ func (s *SMTPMessage) Subject(sub string) *SMTPMessage {
 	return s
 }
 
-// Body adds the message body to the message
+// Body adds the plain text message body to the message. If HTMLBody is
+// also set, the two are rendered as a multipart/alternative part.
 // @example
 // ```javascript
 // const smtp = require('nuclei/smtp');
@@ -73,6 +132,79 @@ func (s *SMTPMessage) Subject(sub string) *SMTPMessage {
 // ```
 func (s *SMTPMessage) Body(msg []byte) *SMTPMessage {
 	s.msg = msg
+	s.cachedBody = nil
+	return s
+}
+
+// HTMLBody adds an HTML alternative for the message body, rendered
+// alongside the plain text body as a multipart/alternative part.
+// @example
+// ```javascript
+// const smtp = require('nuclei/smtp');
+// const message = new smtp.SMTPMessage();
+// message.Body('hello');
+// message.HTMLBody('<b>hello</b>');
+// ```
+func (s *SMTPMessage) HTMLBody(html []byte) *SMTPMessage {
+	s.html = html
+	s.cachedBody = nil
+	return s
+}
+
+// Embed adds an inline resource (eg. an image) referenced from the HTML
+// body via its content id (`cid:<cid>`). Embeds are rendered as a
+// multipart/related part wrapping the plain/HTML alternative.
+// @example
+// ```javascript
+// const smtp = require('nuclei/smtp');
+// const message = new smtp.SMTPMessage();
+// message.HTMLBody('<img src="cid:logo">');
+// message.Embed('logo', 'logo.png', logoBytes);
+// ```
+func (s *SMTPMessage) Embed(cid, filename string, data []byte) *SMTPMessage {
+	s.embeds = append(s.embeds, attachmentPart{filename: filename, data: data, cid: cid})
+	s.cachedBody = nil
+	return s
+}
+
+// AddAttachment adds a file attachment to the message. Multiple
+// attachments may be added and are rendered as sibling parts of the
+// outermost multipart/mixed part.
+// @example
+// ```javascript
+// const smtp = require('nuclei/smtp');
+// const message = new smtp.SMTPMessage();
+// message.AddAttachment('file.txt', 'hello');
+// ```
+func (s *SMTPMessage) AddAttachment(filename string, data []byte) *SMTPMessage {
+	s.attachments = append(s.attachments, attachmentPart{filename: filename, data: data})
+	s.cachedBody = nil
+	return s
+}
+
+// Attachment adds an attachment to the message
+//
+// Deprecated: use AddAttachment instead.
+// @example
+// ```javascript
+// const smtp = require('nuclei/smtp');
+// const message = new smtp.SMTPMessage();
+// message.Attachment('file.txt', 'hello');
+// ```
+func (s *SMTPMessage) Attachment(filename string, data []byte) *SMTPMessage {
+	return s.AddAttachment(filename, data)
+}
+
+// AddHeader adds an arbitrary header (eg. Reply-To, Message-ID, Date) to
+// the message
+// @example
+// ```javascript
+// const smtp = require('nuclei/smtp');
+// const message = new smtp.SMTPMessage();
+// message.AddHeader('Reply-To', 'xyz@projectdiscovery.io');
+// ```
+func (s *SMTPMessage) AddHeader(k, v string) *SMTPMessage {
+	s.headers = append(s.headers, [2]string{k, v})
 	return s
 }
 
@@ -89,7 +221,10 @@ func (s *SMTPMessage) Auth(username, password string) *SMTPMessage {
 	return s
 }
 
-// String returns the string representation of the message
+// String returns the string representation of the message, assembling
+// the nested MIME structure mixed(related(alternative(plain, html),
+// embeds), attachments) from whichever of HTMLBody/Embed/AddAttachment
+// were used.
 // @example
 // ```javascript
 // const smtp = require('nuclei/smtp');
@@ -98,53 +233,194 @@ func (s *SMTPMessage) Auth(username, password string) *SMTPMessage {
 // message.To('xyz2@projectdiscoveyr.io');
 // message.Subject('hello');
 // message.Body('hello');
-// message.Attachment('file.txt', 'hello');
+// message.AddAttachment('file.txt', 'hello');
 // log(message.String());
 // ```
 func (s *SMTPMessage) String() string {
+	headers, body := s.render()
+
 	var buff bytes.Buffer
-	tw := textproto.NewWriter(bufio.NewWriter(&buff))
+	if s.dkimSignature != "" {
+		buff.WriteString(s.dkimSignature)
+	}
+	buff.WriteString(headers)
+	buff.WriteString("\r\n")
+	buff.WriteString(body)
+	return buff.String()
+}
+
+// render builds the rendered header block and MIME body independently
+// (and without the DKIM-Signature header, if any), so that helpers such
+// as DKIM signing can canonicalize them without re-deriving the
+// (randomly boundaried) MIME tree.
+func (s *SMTPMessage) render() (headers, body string) {
+	part := s.body()
+	if s.smime != nil {
+		part = s.smime.mimePart()
+	}
 
+	var hb bytes.Buffer
+	tw := textproto.NewWriter(bufio.NewWriter(&hb))
+	if s.from != "" {
+		_ = tw.PrintfLine("From: %s", s.from)
+	}
 	_ = tw.PrintfLine("To: %s", strings.Join(s.to, ","))
+	if len(s.cc) > 0 {
+		_ = tw.PrintfLine("Cc: %s", strings.Join(s.cc, ","))
+	}
 	if s.sub != "" {
 		_ = tw.PrintfLine("Subject: %s", s.sub)
 	}
-
+	for _, h := range s.headers {
+		_ = tw.PrintfLine("%s: %s", h[0], h[1])
+	}
 	_ = tw.PrintfLine("MIME-Version: 1.0")
-	if s.attachment != "" {
-		boundary := "my-boundary-12345"
-		_ = tw.PrintfLine("Content-Type: multipart/mixed; boundary=%s", boundary)
-		_ = tw.PrintfLine("\r\n--%s", boundary)
-		_ = tw.PrintfLine("Content-Type: text/plain; charset=\"utf-8\"")
-		_ = tw.PrintfLine("\r\n%s", s.msg)
-		_ = tw.PrintfLine("\r\n--%s", boundary)
-		_ = tw.PrintfLine("Content-Type: application/octet-stream; name=\"%s\"", s.attachment)
-		_ = tw.PrintfLine("Content-Transfer-Encoding: base64")
-		_ = tw.PrintfLine("Content-Disposition: attachment; filename=\"%s\"", s.attachment)
-		encoded := base64.StdEncoding.EncodeToString(s.attachData)
-		for i := 0; i < len(encoded); i += 76 {
-			end := i + 76
-			if end > len(encoded) {
-				end = len(encoded)
-			}
-			_ = tw.PrintfLine("%s", encoded[i:end])
+	for _, h := range part.headers {
+		_ = tw.PrintfLine("%s: %s", h[0], h[1])
+	}
+
+	return hb.String(), part.body
+}
+
+// body assembles the full MIME body of the message:
+// mixed(related(alternative(plain, html), embeds), attachments), skipping
+// any layer that wasn't used. The result is memoized on the struct, since
+// each layer's boundary is randomly generated — recomputing it on every
+// call would make the body (and therefore the DKIM signature over it)
+// change on every render.
+func (s *SMTPMessage) body() mimePart {
+	if s.cachedBody != nil {
+		return *s.cachedBody
+	}
+
+	body := s.textPart()
+	if len(s.html) > 0 {
+		body = newMultipart("alternative", newBoundary(), []mimePart{body, s.htmlPart()})
+	}
+	if len(s.embeds) > 0 {
+		parts := []mimePart{body}
+		for _, embed := range s.embeds {
+			parts = append(parts, attachmentMimePart(embed, true))
 		}
-		_ = tw.PrintfLine("\r\n--%s--", boundary)
-	} else {
-		_ = tw.PrintfLine("\r\n%s", s.msg)
+		body = newMultipart("related", newBoundary(), parts)
 	}
-	return buff.String()
+	if len(s.attachments) > 0 {
+		parts := []mimePart{body}
+		for _, attachment := range s.attachments {
+			parts = append(parts, attachmentMimePart(attachment, false))
+		}
+		body = newMultipart("mixed", newBoundary(), parts)
+	}
+
+	s.cachedBody = &body
+	return body
 }
 
-// Attachment adds an attachment to the message
-// @example
-// ```javascript
-// const smtp = require('nuclei/smtp');
-// const message = new smtp.SMTPMessage();
-// message.Attachment('file.txt', 'hello');
-// ```
-func (s *SMTPMessage) Attachment(filename string, data []byte) *SMTPMessage {
-	s.attachment = filename
-	s.attachData = data
-	return s
+// textPart returns the text/plain part for the message.
+func (s *SMTPMessage) textPart() mimePart {
+	return mimePart{
+		headers: [][2]string{
+			{"Content-Type", `text/plain; charset="utf-8"`},
+			{"Content-Transfer-Encoding", "quoted-printable"},
+		},
+		body: encodeQuotedPrintable(s.msg),
+	}
+}
+
+// htmlPart returns the text/html part for the message.
+func (s *SMTPMessage) htmlPart() mimePart {
+	return mimePart{
+		headers: [][2]string{
+			{"Content-Type", `text/html; charset="utf-8"`},
+			{"Content-Transfer-Encoding", "quoted-printable"},
+		},
+		body: encodeQuotedPrintable(s.html),
+	}
+}
+
+// attachmentMimePart renders an attachmentPart as a MIME part, either
+// inline (Content-Disposition: inline, with a Content-ID for cid:
+// references) or as a regular attachment.
+func attachmentMimePart(a attachmentPart, inline bool) mimePart {
+	headers := [][2]string{
+		{"Content-Type", fmt.Sprintf(`application/octet-stream; name="%s"`, a.filename)},
+		{"Content-Transfer-Encoding", "base64"},
+	}
+	disposition := "attachment"
+	if inline {
+		disposition = "inline"
+		headers = append(headers, [2]string{"Content-ID", fmt.Sprintf("<%s>", a.cid)})
+	}
+	headers = append(headers, [2]string{"Content-Disposition", fmt.Sprintf(`%s; filename="%s"`, disposition, a.filename)})
+	return mimePart{headers: headers, body: encodeBase64Lines(a.data)}
+}
+
+// mimePart is a single part of a (possibly nested) multipart MIME
+// message: its own headers, plus an already-encoded body.
+type mimePart struct {
+	headers [][2]string
+	body    string
+}
+
+// write renders the part's headers followed by a blank line and its body.
+func (p mimePart) write(buf *bytes.Buffer) {
+	for _, h := range p.headers {
+		fmt.Fprintf(buf, "%s: %s\r\n", h[0], h[1])
+	}
+	buf.WriteString("\r\n")
+	buf.WriteString(p.body)
+}
+
+// newMultipart wraps parts inside a multipart/<subtype> container using
+// boundary, returning the resulting Content-Type header and body as a
+// mimePart of its own so containers can be nested.
+func newMultipart(subtype, boundary string, parts []mimePart) mimePart {
+	var buf bytes.Buffer
+	for _, p := range parts {
+		fmt.Fprintf(&buf, "--%s\r\n", boundary)
+		p.write(&buf)
+		buf.WriteString("\r\n")
+	}
+	fmt.Fprintf(&buf, "--%s--\r\n", boundary)
+
+	return mimePart{
+		headers: [][2]string{
+			{"Content-Type", fmt.Sprintf("multipart/%s; boundary=%q", subtype, boundary)},
+		},
+		body: buf.String(),
+	}
+}
+
+// newBoundary returns a random MIME part boundary generated from
+// crypto/rand.
+func newBoundary() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return fmt.Sprintf("nuclei-boundary-%x", buf)
+}
+
+// encodeQuotedPrintable encodes data as quoted-printable, used for the
+// plain and HTML text parts.
+func encodeQuotedPrintable(data []byte) string {
+	var buf bytes.Buffer
+	w := quotedprintable.NewWriter(&buf)
+	_, _ = w.Write(data)
+	_ = w.Close()
+	return buf.String()
+}
+
+// encodeBase64Lines base64-encodes data wrapped at 76 columns, used for
+// embeds and attachments.
+func encodeBase64Lines(data []byte) string {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var buf bytes.Buffer
+	for i := 0; i < len(encoded); i += 76 {
+		end := i + 76
+		if end > len(encoded) {
+			end = len(encoded)
+		}
+		buf.WriteString(encoded[i:end])
+		buf.WriteString("\r\n")
+	}
+	return buf.String()
 }
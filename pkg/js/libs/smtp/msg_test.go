@@ -0,0 +1,56 @@
+package smtp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSMTPMessageStringDeterministic(t *testing.T) {
+	message := &SMTPMessage{}
+	message.From("sender@example.com")
+	message.To("to@example.com")
+	message.Subject("hello")
+	message.Body([]byte("plain body"))
+	message.HTMLBody([]byte("<b>html body</b>"))
+	message.Embed("logo", "logo.png", []byte{1, 2, 3, 4})
+	message.AddAttachment("file.txt", []byte("attachment data"))
+
+	first := message.String()
+	second := message.String()
+	require.Equal(t, first, second, "repeated String() calls must render the same MIME boundaries")
+}
+
+func TestSMTPMessageStringNesting(t *testing.T) {
+	message := &SMTPMessage{}
+	message.From("sender@example.com")
+	message.To("to@example.com")
+	message.Subject("hello")
+	message.Body([]byte("plain body"))
+	message.HTMLBody([]byte("<b>html body</b>"))
+	message.Embed("logo", "logo.png", []byte{1, 2, 3, 4})
+	message.AddAttachment("file.txt", []byte("attachment data"))
+
+	raw := message.String()
+	require.Contains(t, raw, "Content-Type: multipart/mixed")
+	require.Contains(t, raw, "Content-Type: multipart/related")
+	require.Contains(t, raw, "Content-Type: multipart/alternative")
+	require.Contains(t, raw, `Content-Disposition: inline; filename="logo.png"`)
+	require.Contains(t, raw, `Content-Disposition: attachment; filename="file.txt"`)
+}
+
+func TestSMTPMessageBccOmittedFromHeaders(t *testing.T) {
+	message := &SMTPMessage{}
+	message.From("sender@example.com")
+	message.To("to@example.com")
+	message.Cc("cc@example.com")
+	message.Bcc("bcc@example.com")
+	message.Subject("hello")
+	message.Body([]byte("plain body"))
+
+	raw := message.String()
+	require.Contains(t, raw, "Cc: cc@example.com")
+	require.False(t, strings.Contains(raw, "bcc@example.com"), "bcc recipients must not be rendered")
+	require.Equal(t, []string{"to@example.com", "cc@example.com", "bcc@example.com"}, message.Recipients())
+}
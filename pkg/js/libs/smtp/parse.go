@@ -0,0 +1,218 @@
+package smtp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ParseEML parses a raw RFC 5322 message (eg. the contents of a `.eml`
+// file) into an SMTPMessage, recovering the From/To/Subject headers, the
+// plain and HTML bodies, and every attachment/inline part from its MIME
+// structure. This lets template authors load a canned message from disk,
+// mutate a couple of fields via the usual chainable setters, and resend
+// it.
+// @example
+// ```javascript
+// const smtp = require('nuclei/smtp');
+// const message = smtp.ParseEML(fileContents);
+// message.Subject('updated subject');
+// ```
+func ParseEML(raw []byte) (*SMTPMessage, error) {
+	parsed, err := mail.ReadMessage(bytes.NewReader(normalizeLineEndings(raw)))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse eml message: %w", err)
+	}
+
+	msg := &SMTPMessage{}
+	msg.from = decodeHeaderWord(parsed.Header.Get("From"))
+	if to, err := parseEMLAddressList(parsed.Header.Get("To")); err != nil {
+		return nil, fmt.Errorf("could not parse eml To header: %w", err)
+	} else {
+		msg.to = to
+	}
+	if cc, err := parseEMLAddressList(parsed.Header.Get("Cc")); err != nil {
+		return nil, fmt.Errorf("could not parse eml Cc header: %w", err)
+	} else {
+		msg.cc = cc
+	}
+	msg.sub = decodeHeaderWord(parsed.Header.Get("Subject"))
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil {
+		// missing (or invalid) Content-Type means a plain text body
+		body, rerr := io.ReadAll(parsed.Body)
+		if rerr != nil {
+			return nil, fmt.Errorf("could not read eml body: %w", rerr)
+		}
+		msg.msg = body
+		return msg, nil
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		if err := parseEMLMultipart(msg, parsed.Body, params["boundary"]); err != nil {
+			return nil, err
+		}
+		return msg, nil
+	}
+
+	body, err := decodeEMLPart(parsed.Header.Get("Content-Transfer-Encoding"), parsed.Body)
+	if err != nil {
+		return nil, err
+	}
+	if mediaType == "text/html" {
+		msg.html = body
+	} else {
+		msg.msg = body
+	}
+	return msg, nil
+}
+
+// FromFile reads a raw `.eml` file from disk and parses it with ParseEML.
+// @example
+// ```javascript
+// const smtp = require('nuclei/smtp');
+// const message = smtp.FromFile('/tmp/sample.eml');
+// ```
+func FromFile(path string) (*SMTPMessage, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read eml file: %w", err)
+	}
+	return ParseEML(raw)
+}
+
+// parseEMLMultipart walks a multipart MIME tree, populating msg with the
+// plain/HTML bodies and every attachment or inline part found. It
+// recurses to handle a multipart/related (or multipart/alternative) part
+// nested inside another multipart part.
+func parseEMLMultipart(msg *SMTPMessage, r io.Reader, boundary string) error {
+	if boundary == "" {
+		return fmt.Errorf("could not parse eml message: missing multipart boundary")
+	}
+
+	mr := multipart.NewReader(r, boundary)
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("could not read eml part: %w", err)
+		}
+
+		mediaType, params, err := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		if err != nil {
+			mediaType = "text/plain"
+		}
+
+		if strings.HasPrefix(mediaType, "multipart/") {
+			if err := parseEMLMultipart(msg, part, params["boundary"]); err != nil {
+				return err
+			}
+			continue
+		}
+
+		data, err := decodeEMLPart(part.Header.Get("Content-Transfer-Encoding"), part)
+		if err != nil {
+			return err
+		}
+
+		filename := emlPartFilename(part, params)
+		cid := strings.Trim(part.Header.Get("Content-ID"), "<>")
+		switch {
+		case filename != "":
+			if cid != "" || strings.HasPrefix(part.Header.Get("Content-Disposition"), "inline") {
+				msg.embeds = append(msg.embeds, attachmentPart{filename: filename, data: data, cid: cid})
+			} else {
+				msg.attachments = append(msg.attachments, attachmentPart{filename: filename, data: data})
+			}
+		case mediaType == "text/html":
+			msg.html = data
+		default:
+			msg.msg = data
+		}
+	}
+}
+
+// emlPartFilename recovers a part's filename from its Content-Disposition
+// header, falling back to the Content-Type "name" parameter.
+func emlPartFilename(part *multipart.Part, typeParams map[string]string) string {
+	if filename := part.FileName(); filename != "" {
+		return filename
+	}
+	return typeParams["name"]
+}
+
+// decodeEMLPart decodes a MIME part's body according to its
+// Content-Transfer-Encoding, returning a descriptive error instead of
+// panicking on malformed input. Encodings other than quoted-printable and
+// base64 (eg. 7bit, 8bit, binary) are read verbatim.
+func decodeEMLPart(encoding string, r io.Reader) ([]byte, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "quoted-printable":
+		data, err := io.ReadAll(quotedprintable.NewReader(r))
+		if err != nil {
+			return nil, fmt.Errorf("could not decode quoted-printable part: %w", err)
+		}
+		return data, nil
+	case "base64":
+		data, err := io.ReadAll(base64.NewDecoder(base64.StdEncoding, r))
+		if err != nil {
+			return nil, fmt.Errorf("could not decode base64 part: %w", err)
+		}
+		return data, nil
+	default:
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("could not read part: %w", err)
+		}
+		return data, nil
+	}
+}
+
+var bareLineFeedRegexp = regexp.MustCompile(`\r?\n`)
+
+// normalizeLineEndings rewrites bare LF (and CRLF) line endings to CRLF,
+// since mail.ReadMessage and multipart.Reader otherwise treat a
+// LF-terminated .eml as a single unterminated header line.
+func normalizeLineEndings(raw []byte) []byte {
+	return bareLineFeedRegexp.ReplaceAll(raw, []byte("\r\n"))
+}
+
+// decodeHeaderWord decodes an RFC 2047 encoded-word header value (eg.
+// `=?UTF-8?B?...?=`), returning the input unchanged if it isn't encoded.
+func decodeHeaderWord(value string) string {
+	decoded, err := (&mime.WordDecoder{}).DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
+
+// parseEMLAddressList parses a To/Cc header value into its bare email
+// addresses via net/mail, rather than naively splitting on ",", which
+// breaks on a quoted display name containing a comma (eg. `"Doe, John"
+// <john@example.com>`). Returns nil, nil for an empty header.
+func parseEMLAddressList(header string) ([]string, error) {
+	if header == "" {
+		return nil, nil
+	}
+	parsed, err := mail.ParseAddressList(header)
+	if err != nil {
+		return nil, err
+	}
+	addresses := make([]string, 0, len(parsed))
+	for _, addr := range parsed {
+		addresses = append(addresses, addr.Address)
+	}
+	return addresses, nil
+}
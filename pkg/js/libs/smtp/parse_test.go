@@ -0,0 +1,61 @@
+package smtp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEMLRoundTrip(t *testing.T) {
+	built := &SMTPMessage{}
+	built.From("sender@example.com")
+	built.To("to@example.com")
+	built.Cc("cc@example.com")
+	built.Subject("round trip")
+	built.Body([]byte("hello plain body"))
+	built.HTMLBody([]byte("<b>hello</b> html body"))
+	built.Embed("logo", "logo.png", []byte{0x89, 0x50, 0x4e, 0x47})
+	built.AddAttachment("file.txt", []byte("attachment contents"))
+
+	parsed, err := ParseEML([]byte(built.String()))
+	require.NoError(t, err)
+
+	require.Equal(t, built.from, parsed.from)
+	require.Equal(t, built.to, parsed.to)
+	require.Equal(t, built.cc, parsed.cc)
+	require.Equal(t, built.sub, parsed.sub)
+	require.Equal(t, built.msg, parsed.msg)
+	require.Equal(t, built.html, parsed.html)
+
+	require.Len(t, parsed.embeds, 1)
+	require.Equal(t, "logo.png", parsed.embeds[0].filename)
+	require.Equal(t, "logo", parsed.embeds[0].cid)
+	require.Equal(t, []byte{0x89, 0x50, 0x4e, 0x47}, parsed.embeds[0].data)
+
+	require.Len(t, parsed.attachments, 1)
+	require.Equal(t, "file.txt", parsed.attachments[0].filename)
+	require.Equal(t, []byte("attachment contents"), parsed.attachments[0].data)
+}
+
+func TestParseEMLPlainNoContentType(t *testing.T) {
+	raw := "From: sender@example.com\r\nTo: to@example.com\r\nSubject: plain\r\n\r\nplain body\r\n"
+
+	parsed, err := ParseEML([]byte(raw))
+	require.NoError(t, err)
+	require.Equal(t, "sender@example.com", parsed.from)
+	require.Equal(t, []string{"to@example.com"}, parsed.to)
+	require.Equal(t, "plain body\r\n", string(parsed.msg))
+}
+
+func TestParseEMLMalformedBase64(t *testing.T) {
+	raw := "From: sender@example.com\r\n" +
+		"To: to@example.com\r\n" +
+		"Content-Type: application/octet-stream; name=\"file.bin\"\r\n" +
+		"Content-Transfer-Encoding: base64\r\n" +
+		"Content-Disposition: attachment; filename=\"file.bin\"\r\n" +
+		"\r\n" +
+		"not-valid-base64!!!\r\n"
+
+	_, err := ParseEML([]byte(raw))
+	require.Error(t, err)
+}
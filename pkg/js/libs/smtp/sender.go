@@ -0,0 +1,302 @@
+package smtp
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime/multipart"
+	"net"
+	"net/http"
+	nsmtp "net/smtp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ses"
+)
+
+// Sender transmits an already built SMTPMessage to every recipient in its
+// envelope (To, Cc and Bcc).
+type Sender interface {
+	Send(ctx context.Context, msg *SMTPMessage) error
+}
+
+// SenderOptions configures the backend returned by NewSender. Type
+// selects the backend ("smtp", the default, "mailgun" or "ses"); the
+// remaining fields are only read by the backend(s) that need them.
+// @example
+// ```javascript
+// const smtp = require('nuclei/smtp');
+// const sender = smtp.NewSender({type: 'smtp', host: 'smtp.example.com', port: 587, starttls: true});
+// ```
+type SenderOptions struct {
+	Type string
+
+	// smtp backend
+	Host      string
+	Port      int
+	STARTTLS  bool
+	TLS       bool
+	LocalName string
+	Auth      string
+	Username  string
+	Password  string
+
+	// mailgun backend
+	Domain string
+	APIKey string
+
+	// ses backend
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// NewSender builds a Sender backend from opts. The default (and zero
+// value) backend is "smtp", which transmits over net/smtp with optional
+// STARTTLS/TLS and configurable SASL auth; "mailgun" and "ses" transmit
+// the same SMTPMessage.String() MIME output over the Mailgun and Amazon
+// SES HTTP APIs respectively, letting template authors switch transports
+// without rewriting message construction code.
+// @example
+// ```javascript
+// const smtp = require('nuclei/smtp');
+// const sender = smtp.NewSender({type: 'mailgun', domain: 'mg.example.com', apiKey: 'key-xxx'});
+// sender.Send(message);
+// ```
+func NewSender(opts SenderOptions) (Sender, error) {
+	switch strings.ToLower(opts.Type) {
+	case "", "smtp":
+		return &SMTPSender{opts: opts}, nil
+	case "mailgun":
+		if opts.Domain == "" || opts.APIKey == "" {
+			return nil, fmt.Errorf("mailgun sender requires domain and apiKey")
+		}
+		return &MailgunSender{opts: opts, client: http.DefaultClient}, nil
+	case "ses":
+		if opts.Region == "" || opts.AccessKeyID == "" || opts.SecretAccessKey == "" {
+			return nil, fmt.Errorf("ses sender requires region, accessKeyId and secretAccessKey")
+		}
+		return &SESSender{opts: opts}, nil
+	default:
+		return nil, fmt.Errorf("unsupported sender type: %s", opts.Type)
+	}
+}
+
+// SMTPSender is the default Sender backend, transmitting messages over
+// net/smtp with optional STARTTLS/TLS and a configurable SASL auth
+// mechanism.
+type SMTPSender struct {
+	opts SenderOptions
+}
+
+// Send dials opts.Host:Port, optionally upgrades to TLS, authenticates
+// and transmits msg to every recipient in its envelope.
+func (s *SMTPSender) Send(ctx context.Context, msg *SMTPMessage) error {
+	addr := fmt.Sprintf("%s:%d", s.opts.Host, s.opts.Port)
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("could not dial smtp server: %w", err)
+	}
+	if s.opts.TLS {
+		conn = tls.Client(conn, &tls.Config{ServerName: s.opts.Host})
+	}
+
+	client, err := nsmtp.NewClient(conn, s.opts.Host)
+	if err != nil {
+		return fmt.Errorf("could not create smtp client: %w", err)
+	}
+	defer client.Close()
+
+	localName := s.opts.LocalName
+	if localName == "" {
+		localName = "localhost"
+	}
+	if err := client.Hello(localName); err != nil {
+		return fmt.Errorf("could not send ehlo: %w", err)
+	}
+
+	if s.opts.STARTTLS && !s.opts.TLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: s.opts.Host}); err != nil {
+				return fmt.Errorf("could not start tls: %w", err)
+			}
+		}
+	}
+
+	if s.opts.Username != "" {
+		auth, err := s.auth()
+		if err != nil {
+			return err
+		}
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("could not authenticate: %w", err)
+		}
+	}
+
+	if err := client.Mail(msg.from); err != nil {
+		return fmt.Errorf("could not set from address: %w", err)
+	}
+	for _, recipient := range msg.Recipients() {
+		if err := client.Rcpt(recipient); err != nil {
+			return fmt.Errorf("could not add recipient %s: %w", recipient, err)
+		}
+	}
+
+	wc, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("could not open data writer: %w", err)
+	}
+	if _, err := wc.Write([]byte(msg.String())); err != nil {
+		return fmt.Errorf("could not write message: %w", err)
+	}
+	if err := wc.Close(); err != nil {
+		return fmt.Errorf("could not finalize message: %w", err)
+	}
+	return client.Quit()
+}
+
+// auth builds the configured SASL authentication mechanism, defaulting to
+// PLAIN.
+func (s *SMTPSender) auth() (nsmtp.Auth, error) {
+	switch AuthMechanism(strings.ToUpper(s.opts.Auth)) {
+	case "", AuthPlain:
+		return nsmtp.PlainAuth("", s.opts.Username, s.opts.Password, s.opts.Host), nil
+	case AuthLogin:
+		return &loginAuth{username: s.opts.Username, password: s.opts.Password}, nil
+	case AuthCRAMMD5:
+		return nsmtp.CRAMMD5Auth(s.opts.Username, s.opts.Password), nil
+	case AuthXOAuth2:
+		return &xoauth2Auth{username: s.opts.Username, token: s.opts.Password}, nil
+	default:
+		return nil, fmt.Errorf("unsupported auth mechanism: %s", s.opts.Auth)
+	}
+}
+
+// AuthMechanism is the SASL mechanism used to authenticate against an
+// SMTP server.
+type AuthMechanism string
+
+const (
+	AuthPlain   AuthMechanism = "PLAIN"
+	AuthLogin   AuthMechanism = "LOGIN"
+	AuthCRAMMD5 AuthMechanism = "CRAM-MD5"
+	AuthXOAuth2 AuthMechanism = "XOAUTH2"
+)
+
+// loginAuth implements the non-standard but widely supported LOGIN SASL
+// mechanism, which net/smtp does not provide out of the box.
+type loginAuth struct {
+	username string
+	password string
+}
+
+func (a *loginAuth) Start(_ *nsmtp.ServerInfo) (string, []byte, error) {
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	switch strings.ToLower(string(fromServer)) {
+	case "username:":
+		return []byte(a.username), nil
+	case "password:":
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("unexpected login challenge: %s", fromServer)
+	}
+}
+
+// xoauth2Auth implements the XOAUTH2 SASL mechanism used by providers
+// such as Gmail, where token is a valid OAuth2 access token.
+type xoauth2Auth struct {
+	username string
+	token    string
+}
+
+func (a *xoauth2Auth) Start(_ *nsmtp.ServerInfo) (string, []byte, error) {
+	resp := fmt.Sprintf("user=%s\x01auth=Bearer %s\x01\x01", a.username, a.token)
+	return "XOAUTH2", []byte(resp), nil
+}
+
+func (a *xoauth2Auth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if more {
+		return nil, fmt.Errorf("xoauth2 authentication failed: %s", fromServer)
+	}
+	return nil, nil
+}
+
+// MailgunSender transmits messages through Mailgun's HTTP API
+// (https://documentation.mailgun.com/en/latest/api-sending.html#sending),
+// reusing the same SMTPMessage.String() MIME rendering as the smtp
+// backend.
+type MailgunSender struct {
+	opts   SenderOptions
+	client *http.Client
+}
+
+// Send posts msg.String() as a raw MIME message to Mailgun's
+// `/messages.mime` endpoint.
+func (m *MailgunSender) Send(ctx context.Context, msg *SMTPMessage) error {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	_ = writer.WriteField("to", strings.Join(msg.Recipients(), ","))
+	_ = writer.WriteField("message", msg.String())
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("could not build mailgun request body: %w", err)
+	}
+
+	endpoint := fmt.Sprintf("https://api.mailgun.net/v3/%s/messages.mime", m.opts.Domain)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, &body)
+	if err != nil {
+		return fmt.Errorf("could not build mailgun request: %w", err)
+	}
+	req.SetBasicAuth("api", m.opts.APIKey)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not send mailgun request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("mailgun returned unexpected status: %s", resp.Status)
+	}
+	return nil
+}
+
+// SESSender transmits messages through Amazon SES's SendRawEmail API,
+// reusing the same SMTPMessage.String() MIME rendering as the smtp
+// backend.
+type SESSender struct {
+	opts SenderOptions
+}
+
+// Send calls SendRawEmail with msg.String() as the raw MIME message.
+func (s *SESSender) Send(ctx context.Context, msg *SMTPMessage) error {
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String(s.opts.Region),
+		Credentials: credentials.NewStaticCredentials(s.opts.AccessKeyID, s.opts.SecretAccessKey, ""),
+	})
+	if err != nil {
+		return fmt.Errorf("could not create ses session: %w", err)
+	}
+
+	svc := ses.New(sess)
+	_, err = svc.SendRawEmailWithContext(ctx, &ses.SendRawEmailInput{
+		Source:       aws.String(msg.from),
+		Destinations: aws.StringSlice(msg.Recipients()),
+		RawMessage:   &ses.RawMessage{Data: []byte(msg.String())},
+	})
+	if err != nil {
+		return fmt.Errorf("could not send ses request: %w", err)
+	}
+	return nil
+}
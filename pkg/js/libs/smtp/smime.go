@@ -0,0 +1,112 @@
+package smtp
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+
+	"go.mozilla.org/pkcs7"
+)
+
+// smimePart replaces the usual MIME body tree with a single PKCS#7 part,
+// once SignSMIME or EncryptSMIME has been called.
+type smimePart struct {
+	smimeType string
+	data      []byte
+}
+
+// mimePart renders the PKCS#7 blob as an
+// `application/pkcs7-mime; smime-type=...` part, base64 encoded wrapped
+// at 76 columns.
+func (p *smimePart) mimePart() mimePart {
+	return mimePart{
+		headers: [][2]string{
+			{"Content-Type", fmt.Sprintf(`application/pkcs7-mime; smime-type=%s; name="smime.p7m"`, p.smimeType)},
+			{"Content-Transfer-Encoding", "base64"},
+			{"Content-Disposition", `attachment; filename="smime.p7m"`},
+		},
+		body: encodeBase64Lines(p.data),
+	}
+}
+
+// SignSMIME wraps the rendered message body as a PKCS#7 signed-data
+// S/MIME part (`application/pkcs7-mime; smime-type=signed-data`) that
+// embeds the signed content alongside the signature, using
+// certPEM/keyPEM as the signer's certificate and RSA private key.
+// @example
+// ```javascript
+// const smtp = require('nuclei/smtp');
+// const message = new smtp.SMTPMessage();
+// message.Body('hello');
+// message.SignSMIME(certPem, keyPem);
+// ```
+func (s *SMTPMessage) SignSMIME(certPEM, keyPEM []byte) (*SMTPMessage, error) {
+	cert, err := parseSMIMECertificate(certPEM)
+	if err != nil {
+		return nil, err
+	}
+	key, err := parseRSAPrivateKey(keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse smime private key: %w", err)
+	}
+
+	_, body := s.render()
+	signed, err := pkcs7.NewSignedData([]byte(body))
+	if err != nil {
+		return nil, fmt.Errorf("could not create pkcs7 signed data: %w", err)
+	}
+	if err := signed.AddSigner(cert, key, pkcs7.SignerInfoConfig{}); err != nil {
+		return nil, fmt.Errorf("could not add pkcs7 signer: %w", err)
+	}
+	der, err := signed.Finish()
+	if err != nil {
+		return nil, fmt.Errorf("could not finalize pkcs7 signature: %w", err)
+	}
+
+	s.smime = &smimePart{smimeType: "signed-data", data: der}
+	return s, nil
+}
+
+// EncryptSMIME replaces the rendered message body with a PKCS#7
+// enveloped-data S/MIME part
+// (`application/pkcs7-mime; smime-type=enveloped-data`), encrypted for
+// every recipient certificate in recipientCertsPEM.
+// @example
+// ```javascript
+// const smtp = require('nuclei/smtp');
+// const message = new smtp.SMTPMessage();
+// message.Body('hello');
+// message.EncryptSMIME([recipientCertPem]);
+// ```
+func (s *SMTPMessage) EncryptSMIME(recipientCertsPEM [][]byte) (*SMTPMessage, error) {
+	certs := make([]*x509.Certificate, 0, len(recipientCertsPEM))
+	for _, certPEM := range recipientCertsPEM {
+		cert, err := parseSMIMECertificate(certPEM)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+
+	_, body := s.render()
+	der, err := pkcs7.Encrypt([]byte(body), certs)
+	if err != nil {
+		return nil, fmt.Errorf("could not encrypt pkcs7 data: %w", err)
+	}
+
+	s.smime = &smimePart{smimeType: "enveloped-data", data: der}
+	return s, nil
+}
+
+// parseSMIMECertificate decodes a PEM-encoded X.509 certificate.
+func parseSMIMECertificate(certPEM []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil, fmt.Errorf("could not decode smime certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse smime certificate: %w", err)
+	}
+	return cert, nil
+}
@@ -0,0 +1,111 @@
+package smtp
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mozilla.org/pkcs7"
+)
+
+// generateSMIMEKeyPair returns a self-signed certificate and matching RSA
+// private key, both PEM-encoded, for use as a test signer/recipient.
+func generateSMIMEKeyPair(t *testing.T) (certPEM, keyPEM []byte, cert *x509.Certificate, key *rsa.PrivateKey) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "smtp-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err = x509.ParseCertificate(certDER)
+	require.NoError(t, err)
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+
+	return certPEM, keyPEM, cert, key
+}
+
+func TestSignSMIMERoundTrip(t *testing.T) {
+	certPEM, keyPEM, _, _ := generateSMIMEKeyPair(t)
+
+	message := &SMTPMessage{}
+	message.From("sender@example.com")
+	message.To("recipient@example.com")
+	message.Subject("smime signed")
+	message.Body([]byte("hello, this is signed"))
+
+	_, err := message.SignSMIME(certPEM, keyPEM)
+	require.NoError(t, err)
+
+	raw := message.String()
+	require.Contains(t, raw, `Content-Type: application/pkcs7-mime; smime-type=signed-data`)
+
+	der := extractSMIMEBody(t, raw)
+	p7, err := pkcs7.Parse(der)
+	require.NoError(t, err)
+	require.NoError(t, p7.Verify())
+	require.Equal(t, []byte("hello, this is signed"), p7.Content)
+}
+
+func TestEncryptSMIMERoundTrip(t *testing.T) {
+	certPEM, _, cert, key := generateSMIMEKeyPair(t)
+
+	message := &SMTPMessage{}
+	message.From("sender@example.com")
+	message.To("recipient@example.com")
+	message.Subject("smime encrypted")
+	message.Body([]byte("hello, this is secret"))
+
+	_, err := message.EncryptSMIME([][]byte{certPEM})
+	require.NoError(t, err)
+
+	raw := message.String()
+	require.Contains(t, raw, `Content-Type: application/pkcs7-mime; smime-type=enveloped-data`)
+
+	der := extractSMIMEBody(t, raw)
+	p7, err := pkcs7.Parse(der)
+	require.NoError(t, err)
+
+	decrypted, err := p7.Decrypt(cert, key)
+	require.NoError(t, err)
+	require.Equal(t, []byte("hello, this is secret"), decrypted)
+}
+
+func TestSignSMIMEMalformedPEM(t *testing.T) {
+	message := &SMTPMessage{}
+	message.Body([]byte("hello"))
+
+	_, err := message.SignSMIME([]byte("not a pem"), []byte("not a pem either"))
+	require.Error(t, err)
+}
+
+// extractSMIMEBody base64-decodes the body of the rendered
+// application/pkcs7-mime part, skipping headers.
+func extractSMIMEBody(t *testing.T, raw string) []byte {
+	t.Helper()
+
+	idx := strings.Index(raw, "\r\n\r\n")
+	require.GreaterOrEqual(t, idx, 0, "could not find end of headers")
+	encoded := strings.ReplaceAll(raw[idx+len("\r\n\r\n"):], "\r\n", "")
+
+	der, err := base64.StdEncoding.DecodeString(encoded)
+	require.NoError(t, err)
+	return der
+}